@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// TraceSpec identifies a single TLA+ spec and config to run the
+// generate-tests -> test -> tla-trace-to-json-trace pipeline against.
+type TraceSpec struct {
+	Name    string
+	TlaFile string
+	CfgFile string
+}
+
+// BatchOptions configures TracesBatch.
+type BatchOptions struct {
+	// Concurrency caps how many specs run at once. Defaults to 1 if <= 0.
+	Concurrency int
+	// FailFast cancels the remaining specs as soon as one fails.
+	FailFast bool
+	// Context, if set, bounds every spec's pipeline run. Defaults to
+	// context.Background().
+	Context context.Context
+	// Decoder, if set, is used to additionally decode every resulting
+	// trace into TraceResult.States.
+	Decoder *Decoder
+}
+
+// TraceResult is the outcome of running TraceSpec's pipeline: the raw
+// traces (one per generated test, as returned by Traces), the same traces
+// decoded through Decoder when one was configured, and any error that
+// spec produced.
+type TraceResult struct {
+	Spec   TraceSpec
+	Traces [][]byte
+	States [][]interface{}
+	Err    error
+}
+
+// tracesPipeline is the per-spec pipeline TracesBatch fans out over. It is
+// a variable, rather than a direct call to TracesContext, so tests can
+// substitute a fake pipeline without spawning a real modelator process.
+var tracesPipeline = TracesContext
+
+// TracesBatch runs the Traces pipeline for every spec, fanning out across
+// a worker pool instead of callers having to hand-roll one around the
+// single-spec Traces/TracesContext functions.
+func TracesBatch(specs []TraceSpec, opts BatchOptions) ([]TraceResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	parentCtx := opts.Context
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	results := make([]TraceResult, len(specs))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i, spec := range specs {
+		wg.Add(1)
+		go func(i int, spec TraceSpec) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := TraceResult{Spec: spec}
+			result.Traces, result.Err = tracesPipeline(ctx, spec.TlaFile, spec.CfgFile)
+
+			if result.Err == nil && opts.Decoder != nil {
+				result.States = make([][]interface{}, len(result.Traces))
+				for j, trace := range result.Traces {
+					states, err := opts.Decoder.DecodeTrace(trace)
+					if err != nil {
+						result.Err = err
+						break
+					}
+					result.States[j] = states
+				}
+			}
+
+			results[i] = result
+
+			if result.Err != nil && opts.FailFast {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = result.Err
+				}
+				mu.Unlock()
+				cancel()
+			}
+		}(i, spec)
+	}
+
+	wg.Wait()
+	return results, firstErr
+}
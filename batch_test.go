@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func withFakeTracesPipeline(t *testing.T, fake func(ctx context.Context, tlaFile string, cfgFile string) ([][]byte, error)) {
+	t.Helper()
+	original := tracesPipeline
+	tracesPipeline = fake
+	t.Cleanup(func() { tracesPipeline = original })
+}
+
+func TestTracesBatchFailFastCancelsRemainingSpecs(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	withFakeTracesPipeline(t, func(ctx context.Context, tlaFile string, cfgFile string) ([][]byte, error) {
+		if tlaFile == "bad.tla" {
+			return nil, errBoom
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return [][]byte{[]byte(`[]`)}, nil
+		}
+	})
+
+	specs := []TraceSpec{
+		{Name: "bad", TlaFile: "bad.tla"},
+		{Name: "slow", TlaFile: "slow.tla"},
+	}
+
+	results, err := TracesBatch(specs, BatchOptions{Concurrency: 2, FailFast: true})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("TracesBatch error = %v, want %v", err, errBoom)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if !errors.Is(results[1].Err, context.Canceled) {
+		t.Fatalf("results[1].Err = %v, want context.Canceled", results[1].Err)
+	}
+}
+
+func TestTracesBatchConcurrencyLimit(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	withFakeTracesPipeline(t, func(ctx context.Context, tlaFile string, cfgFile string) ([][]byte, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		return [][]byte{}, nil
+	})
+
+	specs := make([]TraceSpec, 6)
+	for i := range specs {
+		specs[i] = TraceSpec{Name: string(rune('a' + i))}
+	}
+
+	if _, err := TracesBatch(specs, BatchOptions{Concurrency: 2}); err != nil {
+		t.Fatalf("TracesBatch: %v", err)
+	}
+
+	if maxInFlight > 2 {
+		t.Fatalf("max concurrent pipeline runs = %d, want <= 2", maxInFlight)
+	}
+}
+
+func TestTracesBatchDecodesTracesWhenDecoderConfigured(t *testing.T) {
+	withFakeTracesPipeline(t, func(ctx context.Context, tlaFile string, cfgFile string) ([][]byte, error) {
+		return [][]byte{[]byte(`[{"x":1},{"x":2}]`)}, nil
+	})
+
+	dec := NewDecoder()
+	dec.Register("State", reflect.TypeOf(decoderTestState{}))
+
+	results, err := TracesBatch([]TraceSpec{{Name: "s"}}, BatchOptions{Decoder: dec})
+	if err != nil {
+		t.Fatalf("TracesBatch: %v", err)
+	}
+	if len(results) != 1 || len(results[0].States) != 1 || len(results[0].States[0]) != 2 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
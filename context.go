@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os/exec"
+
+	"github.com/Alexander-N/modelator/schema"
+)
+
+func ModelatorContext(ctx context.Context, modelatorModule string, modelatorMethod string, args ...string) (json.RawMessage, error) {
+	allArgs := append([]string{modelatorModule, modelatorMethod}, args...)
+	cmd := exec.CommandContext(ctx, "modelator", allArgs...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schema.Validate(output, schema.ModelatorOutputSchema); err != nil {
+		return nil, err
+	}
+
+	var modelatorOutput ModelatorOutput
+	json.Unmarshal(output, &modelatorOutput)
+
+	if modelatorOutput.Status == "error" {
+		return nil, newModelatorError(modelatorModule, modelatorMethod, modelatorOutput.Result)
+	} else if modelatorOutput.Status == "success" {
+		return modelatorOutput.Result, nil
+	} else {
+		return nil, newUnexpectedStatusError(modelatorModule, modelatorMethod, modelatorOutput.Status)
+	}
+}
+
+func GenerateTestsContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) ([]GeneratedTest, error) {
+	var generatedTests []GeneratedTest
+	result, err := ModelatorContext(ctx, "tla", "generate-tests", tlaTestsFile, tlaConfigFile)
+	if err != nil {
+		return generatedTests, err
+	}
+	if err := schema.Validate(result, schema.GeneratedTestSchema); err != nil {
+		return generatedTests, err
+	}
+	json.Unmarshal(result, &generatedTests)
+	return generatedTests, nil
+}
+
+func TestContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) (TlaTrace, error) {
+	var tlaTrace TlaTrace
+	result, err := ModelatorContext(ctx, "tlc", "test", tlaTestsFile, tlaConfigFile)
+	if err != nil {
+		return tlaTrace, err
+	}
+	if err := schema.Validate(result, schema.TlaTraceSchema); err != nil {
+		return tlaTrace, err
+	}
+	json.Unmarshal(result, &tlaTrace)
+	return tlaTrace, nil
+}
+
+func TlaTraceToJsonTraceContext(ctx context.Context, tlaTraceFile string) (JsonTrace, error) {
+	var jsonTrace JsonTrace
+	result, err := ModelatorContext(ctx, "tla", "tla-trace-to-json-trace", tlaTraceFile)
+	if err != nil {
+		return jsonTrace, err
+	}
+	if err := schema.Validate(result, schema.JsonTraceSchema); err != nil {
+		return jsonTrace, err
+	}
+	json.Unmarshal(result, &jsonTrace)
+	return jsonTrace, nil
+}
+
+// TracesContext aborts between tests, rather than waiting for all of them
+// to finish, once ctx is done.
+func TracesContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) ([][]byte, error) {
+	session, err := NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	return session.TracesContext(ctx, tlaTestsFile, tlaConfigFile)
+}
+
+func (s *Session) GenerateTestsContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) ([]GeneratedTest, error) {
+	var generatedTests []GeneratedTest
+	result, err := s.callContext(ctx, "tla.generate-tests", []string{tlaTestsFile, tlaConfigFile})
+	if err != nil {
+		return generatedTests, err
+	}
+	if err := schema.Validate(result, schema.GeneratedTestSchema); err != nil {
+		return generatedTests, err
+	}
+	json.Unmarshal(result, &generatedTests)
+	return generatedTests, nil
+}
+
+func (s *Session) TestContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) (TlaTrace, error) {
+	var tlaTrace TlaTrace
+	result, err := s.callContext(ctx, "tlc.test", []string{tlaTestsFile, tlaConfigFile})
+	if err != nil {
+		return tlaTrace, err
+	}
+	if err := schema.Validate(result, schema.TlaTraceSchema); err != nil {
+		return tlaTrace, err
+	}
+	json.Unmarshal(result, &tlaTrace)
+	return tlaTrace, nil
+}
+
+func (s *Session) TlaTraceToJsonTraceContext(ctx context.Context, tlaTraceFile string) (JsonTrace, error) {
+	var jsonTrace JsonTrace
+	result, err := s.callContext(ctx, "tla.tla-trace-to-json-trace", []string{tlaTraceFile})
+	if err != nil {
+		return jsonTrace, err
+	}
+	if err := schema.Validate(result, schema.JsonTraceSchema); err != nil {
+		return jsonTrace, err
+	}
+	json.Unmarshal(result, &jsonTrace)
+	return jsonTrace, nil
+}
+
+// TracesContext checks ctx between each step of the per-test loop, so a
+// cancellation aborts the remaining tests instead of running them all to
+// completion.
+func (s *Session) TracesContext(ctx context.Context, tlaTestsFile string, tlaConfigFile string) ([][]byte, error) {
+	var traces [][]byte
+
+	generatedTests, err := s.GenerateTestsContext(ctx, tlaTestsFile, tlaConfigFile)
+	if err != nil {
+		return traces, err
+	}
+
+	for _, generatedTest := range generatedTests {
+		if err := ctx.Err(); err != nil {
+			return traces, err
+		}
+
+		tlaTrace, err := s.TestContext(ctx, generatedTest.TlaFile, generatedTest.TlaConfigFile)
+		if err != nil {
+			return traces, err
+		}
+
+		jsonTrace, err := s.TlaTraceToJsonTraceContext(ctx, tlaTrace.TlaTraceFile)
+		if err != nil {
+			return traces, err
+		}
+
+		trace, err := ioutil.ReadFile(jsonTrace.JsonTraceFile)
+		if err != nil {
+			return traces, err
+		}
+		traces = append(traces, trace)
+	}
+
+	return traces, nil
+}
+
+// callContext is the context-aware equivalent of call: it aborts and
+// returns ctx.Err() as soon as ctx is done, even if the response from
+// modelator never arrives.
+func (s *Session) callContext(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	type result struct {
+		data json.RawMessage
+		err  error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		data, err := s.call(method, params)
+		ch <- result{data, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.data, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
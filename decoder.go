@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// TypeHook decodes a raw JSON value into a Go value of the given target
+// type, for shapes that don't map cleanly onto encoding/json's defaults —
+// TLA's Set, Function and Record values being the common case. It returns
+// handled == false to let the Decoder fall back to json.Unmarshal.
+type TypeHook func(raw json.RawMessage, target reflect.Type) (value reflect.Value, handled bool, err error)
+
+// Decoder unmarshals the states of a modelator JSON trace (an array of
+// objects, one per step, mapping TLA variable name to JSON value) into a
+// user-registered Go struct type, instead of every caller hand-rolling that
+// decoding themselves.
+type Decoder struct {
+	types map[string]reflect.Type
+	hooks []TypeHook
+}
+
+// NewDecoder returns an empty Decoder. A type must be registered under
+// "State" with Register before DecodeTrace or DecodeTraceStream can be
+// used.
+func NewDecoder() *Decoder {
+	return &Decoder{types: make(map[string]reflect.Type)}
+}
+
+// Register associates name with the Go type that trace states should be
+// decoded into. "State" is the conventional name used by DecodeTrace and
+// DecodeTraceStream for the per-step state type.
+func (d *Decoder) Register(name string, t reflect.Type) {
+	d.types[name] = t
+}
+
+// RegisterTypeHook adds a TypeHook that DecodeTrace/DecodeTraceStream
+// consult, in registration order, before falling back to json.Unmarshal
+// for a struct field.
+func (d *Decoder) RegisterTypeHook(hook TypeHook) {
+	d.hooks = append(d.hooks, hook)
+}
+
+// DecodeTrace decodes every state in trace (a JSON array of state objects)
+// into the type registered under "State", returning one decoded value per
+// step in order.
+func (d *Decoder) DecodeTrace(trace []byte) ([]interface{}, error) {
+	var rawStates []json.RawMessage
+	if err := json.Unmarshal(trace, &rawStates); err != nil {
+		return nil, err
+	}
+
+	states := make([]interface{}, len(rawStates))
+	for i, raw := range rawStates {
+		state, err := d.decodeState(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decoder: state %d: %w", i, err)
+		}
+		states[i] = state
+	}
+	return states, nil
+}
+
+// DecodeTraceStream decodes a JSON trace from r one state at a time,
+// calling fn after each step is decoded. Unlike DecodeTrace it never holds
+// the whole trace in memory, which matters for large counterexamples.
+func (d *Decoder) DecodeTraceStream(r io.Reader, fn func(step int, state interface{}) error) error {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("decoder: expected a JSON array, got %v", tok)
+	}
+
+	for step := 0; dec.More(); step++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+
+		state, err := d.decodeState(raw)
+		if err != nil {
+			return fmt.Errorf("decoder: state %d: %w", step, err)
+		}
+		if err := fn(step, state); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token() // closing ']'
+	return err
+}
+
+func (d *Decoder) decodeState(raw json.RawMessage) (interface{}, error) {
+	t, ok := d.types["State"]
+	if !ok {
+		return nil, fmt.Errorf("decoder: no type registered for %q", "State")
+	}
+
+	target := reflect.New(t)
+	if err := d.decodeInto(raw, target.Elem()); err != nil {
+		return nil, err
+	}
+	return target.Elem().Interface(), nil
+}
+
+// decodeInto fills the fields of elem (a struct value) from the JSON
+// object in raw, consulting the registered TypeHooks before falling back
+// to json.Unmarshal for each field.
+func (d *Decoder) decodeInto(raw json.RawMessage, elem reflect.Value) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	t := elem.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported field: reflect can't set or read it
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+
+		rawValue, ok := fields[name]
+		if !ok {
+			// Fall back to a case-insensitive match, the way encoding/json
+			// does, so a struct field without an explicit json tag still
+			// lines up with a lowercase TLA variable name (e.g. Field X
+			// matching trace key "x").
+			for key, value := range fields {
+				if strings.EqualFold(key, name) {
+					rawValue, ok = value, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		fieldValue := elem.Field(i)
+
+		handled, err := d.applyTypeHooks(rawValue, fieldValue)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+
+		if err := json.Unmarshal(rawValue, fieldValue.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) applyTypeHooks(raw json.RawMessage, fieldValue reflect.Value) (bool, error) {
+	for _, hook := range d.hooks {
+		value, handled, err := hook(raw, fieldValue.Type())
+		if err != nil {
+			return true, err
+		}
+		if handled {
+			fieldValue.Set(value)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decoderTestState struct {
+	X int `json:"x"`
+	y int // unexported: must be skipped, not panic
+}
+
+// decoderUntaggedState has no json tags at all, so Decoder must fall back
+// to a case-insensitive match against the lowercase TLA variable names.
+type decoderUntaggedState struct {
+	N int
+}
+
+func TestDecodeTraceSkipsUnexportedFields(t *testing.T) {
+	dec := NewDecoder()
+	dec.Register("State", reflect.TypeOf(decoderTestState{}))
+
+	trace := []byte(`[{"x":1,"y":10},{"x":2,"y":20}]`)
+	states, err := dec.DecodeTrace(trace)
+	if err != nil {
+		t.Fatalf("DecodeTrace: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("expected 2 states, got %d", len(states))
+	}
+	if got := states[0].(decoderTestState).X; got != 1 {
+		t.Fatalf("states[0].X = %d, want 1", got)
+	}
+	if got := states[1].(decoderTestState).X; got != 2 {
+		t.Fatalf("states[1].X = %d, want 2", got)
+	}
+}
+
+func TestDecodeTraceStream(t *testing.T) {
+	dec := NewDecoder()
+	dec.Register("State", reflect.TypeOf(decoderTestState{}))
+
+	trace := `[{"x":1},{"x":2},{"x":3}]`
+
+	var got []int
+	err := dec.DecodeTraceStream(strings.NewReader(trace), func(step int, state interface{}) error {
+		got = append(got, state.(decoderTestState).X)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DecodeTraceStream: %v", err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("got %v, want [1 2 3]", got)
+	}
+}
+
+func TestDecodeTraceMatchesFieldNameCaseInsensitively(t *testing.T) {
+	dec := NewDecoder()
+	dec.Register("State", reflect.TypeOf(decoderUntaggedState{}))
+
+	states, err := dec.DecodeTrace([]byte(`[{"n":42}]`))
+	if err != nil {
+		t.Fatalf("DecodeTrace: %v", err)
+	}
+	if got := states[0].(decoderUntaggedState).N; got != 42 {
+		t.Fatalf("states[0].N = %d, want 42", got)
+	}
+}
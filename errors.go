@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for the broad classes of failure modelator reports.
+// Callers can check for these with errors.Is, e.g.:
+//
+//	if errors.Is(err, ErrNoTests) { ... }
+var (
+	ErrParseError = errors.New("modelator: parse error")
+	ErrTlcFailure = errors.New("modelator: tlc failure")
+	ErrNoTests    = errors.New("modelator: no tests generated")
+)
+
+// ModelatorError is the structured form of a modelator error result. It
+// preserves the module/method that failed along with the Kind, Message and
+// any extra Details reported by modelator, instead of collapsing them into
+// a single opaque string.
+type ModelatorError struct {
+	Module  string          `json:"module"`
+	Method  string          `json:"method"`
+	Kind    string          `json:"kind"`
+	Message string          `json:"message"`
+	Details json.RawMessage `json:"details"`
+}
+
+func (e *ModelatorError) Error() string {
+	return fmt.Sprintf("modelator: %s.%s: %s: %s", e.Module, e.Method, e.Kind, e.Message)
+}
+
+// Unwrap lets errors.Is match a ModelatorError against the sentinel that
+// corresponds to its Kind.
+func (e *ModelatorError) Unwrap() error {
+	switch e.Kind {
+	case "parse_error":
+		return ErrParseError
+	case "tlc_failure":
+		return ErrTlcFailure
+	case "no_tests":
+		return ErrNoTests
+	default:
+		return nil
+	}
+}
+
+// newModelatorError unmarshals a modelator error result into a
+// ModelatorError, filling in the module and method that produced it.
+func newModelatorError(modelatorModule string, modelatorMethod string, result json.RawMessage) error {
+	modelatorError := &ModelatorError{Module: modelatorModule, Method: modelatorMethod}
+	json.Unmarshal(result, modelatorError)
+	if modelatorError.Message == "" {
+		modelatorError.Message = string(result)
+	}
+	return modelatorError
+}
+
+// newUnexpectedStatusError reports an unrecognized top-level "status" value
+// from modelator. It used to be a panic; callers now get a typed error.
+func newUnexpectedStatusError(modelatorModule string, modelatorMethod string, status string) error {
+	return &ModelatorError{
+		Module:  modelatorModule,
+		Method:  modelatorMethod,
+		Kind:    "unexpected_status",
+		Message: "unexpected status: " + status,
+	}
+}
+
+// newModelatorErrorFromRPCError converts a Session's JSON-RPC error into a
+// ModelatorError, so that a Session failure and a Modelator failure surface
+// through the same type and the same errors.Is sentinels. rpcMethod is the
+// "module.method" string the Session call was made with; rpcErr.Data, when
+// present, is expected to carry the same {kind, message, details} shape as
+// a Modelator error result.
+func newModelatorErrorFromRPCError(rpcMethod string, rpcErr *rpcError) error {
+	modelatorModule, modelatorMethod := splitRPCMethod(rpcMethod)
+	modelatorError := &ModelatorError{
+		Module:  modelatorModule,
+		Method:  modelatorMethod,
+		Message: rpcErr.Message,
+	}
+
+	if len(rpcErr.Data) > 0 {
+		json.Unmarshal(rpcErr.Data, modelatorError)
+	}
+
+	return modelatorError
+}
+
+func splitRPCMethod(rpcMethod string) (modelatorModule string, modelatorMethod string) {
+	if module, method, ok := strings.Cut(rpcMethod, "."); ok {
+		return module, method
+	}
+	return rpcMethod, ""
+}
@@ -2,10 +2,10 @@ package main
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"os/exec"
+
+	"github.com/Alexander-N/modelator/schema"
 )
 
 // From the root of this repository run:
@@ -27,66 +27,57 @@ func main() {
 	}
 }
 
+// Traces opens a Session, runs the generate-tests -> test ->
+// tla-trace-to-json-trace pipeline once over it, and closes the session
+// again. Existing callers see no change in behavior; under the hood this
+// now pays the modelator start-up cost once instead of once per step.
 func Traces(tlaTestsFile string, tlaConfigFile string) ([][]byte, error) {
-	var traces [][]byte
-
-	// generate tests
-	generatedTests, err := GenerateTests(tlaTestsFile, tlaConfigFile)
+	session, err := NewSession()
 	if err != nil {
-		return traces, err
-	}
-
-	// generate json trace for each test
-	for _, generatedTest := range generatedTests {
-
-		// generate tla trace
-		tlaTrace, err := Test(generatedTest.TlaFile, generatedTest.TlaConfigFile)
-		if err != nil {
-			return traces, err
-		}
-
-		// convert tla trace to a json trace
-		jsonTrace, err := TlaTraceToJsonTrace(tlaTrace.TlaTraceFile)
-		if err != nil {
-			return traces, err
-		}
-
-		// read json trace file
-		trace, err := ioutil.ReadFile(jsonTrace.JsonTraceFile)
-		if err != nil {
-			return traces, err
-		}
-		traces = append(traces, trace)
+		return nil, err
 	}
+	defer session.Close()
 
-	return traces, nil
+	return session.Traces(tlaTestsFile, tlaConfigFile)
 }
 
 func GenerateTests(tlaTestsFile string, tlaConfigFile string) ([]GeneratedTest, error) {
 	var generatedTests []GeneratedTest
 	result, err := Modelator("tla", "generate-tests", tlaTestsFile, tlaConfigFile)
-	if err == nil {
-		json.Unmarshal(result, &generatedTests)
+	if err != nil {
+		return generatedTests, err
+	}
+	if err := schema.Validate(result, schema.GeneratedTestSchema); err != nil {
+		return generatedTests, err
 	}
-	return generatedTests, err
+	json.Unmarshal(result, &generatedTests)
+	return generatedTests, nil
 }
 
 func Test(tlaTestsFile string, tlaConfigFile string) (TlaTrace, error) {
 	var tlaTrace TlaTrace
 	result, err := Modelator("tlc", "test", tlaTestsFile, tlaConfigFile)
-	if err == nil {
-		json.Unmarshal(result, &tlaTrace)
+	if err != nil {
+		return tlaTrace, err
+	}
+	if err := schema.Validate(result, schema.TlaTraceSchema); err != nil {
+		return tlaTrace, err
 	}
-	return tlaTrace, err
+	json.Unmarshal(result, &tlaTrace)
+	return tlaTrace, nil
 }
 
 func TlaTraceToJsonTrace(tlaTraceFile string) (JsonTrace, error) {
 	var jsonTrace JsonTrace
 	result, err := Modelator("tla", "tla-trace-to-json-trace", tlaTraceFile)
-	if err == nil {
-		json.Unmarshal(result, &jsonTrace)
+	if err != nil {
+		return jsonTrace, err
+	}
+	if err := schema.Validate(result, schema.JsonTraceSchema); err != nil {
+		return jsonTrace, err
 	}
-	return jsonTrace, err
+	json.Unmarshal(result, &jsonTrace)
+	return jsonTrace, nil
 }
 
 func Modelator(modelatorModule string, modelatorMethod string, args ...string) (json.RawMessage, error) {
@@ -94,20 +85,23 @@ func Modelator(modelatorModule string, modelatorMethod string, args ...string) (
 	cmd := exec.Command("modelator", allArgs...)
 
 	// run command
-	output, err := cmd.Output()
-	fmt.Print("output: ", string(output))
-	fmt.Println("error: ", err)
+	output, _ := cmd.Output()
+
+	// validate against the ModelatorOutput envelope before trusting its shape
+	if err := schema.Validate(output, schema.ModelatorOutputSchema); err != nil {
+		return nil, err
+	}
 
 	// parse its output
 	var modelatorOutput ModelatorOutput
 	json.Unmarshal(output, &modelatorOutput)
 
 	if modelatorOutput.Status == "error" {
-		return nil, errors.New(string(modelatorOutput.Result))
+		return nil, newModelatorError(modelatorModule, modelatorMethod, modelatorOutput.Result)
 	} else if modelatorOutput.Status == "success" {
 		return modelatorOutput.Result, nil
 	} else {
-		panic("[modelator] unexpected status: " + modelatorOutput.Status)
+		return nil, newUnexpectedStatusError(modelatorModule, modelatorMethod, modelatorOutput.Status)
 	}
 }
 
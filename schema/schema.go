@@ -0,0 +1,73 @@
+// Package schema carries the embedded JSON Schemas for the envelopes
+// exchanged with the modelator process (ModelatorOutput, GeneratedTest,
+// TlaTrace, JsonTrace) plus a user-facing Trace schema, and validates JSON
+// payloads against them. This catches contract drift between the Rust side
+// and the Go wrapper before it reaches json.Unmarshal.
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:embed *.schema.json
+var schemaFS embed.FS
+
+// Embedded schemas for the envelopes modelator itself produces, plus a
+// schema for the trace format users decode on their end.
+var (
+	ModelatorOutputSchema = mustLoad("modelator_output.schema.json")
+	GeneratedTestSchema   = mustLoad("generated_test.schema.json")
+	TlaTraceSchema        = mustLoad("tla_trace.schema.json")
+	JsonTraceSchema       = mustLoad("json_trace.schema.json")
+	TraceSchema           = mustLoad("trace.schema.json")
+)
+
+func mustLoad(name string) []byte {
+	data, err := schemaFS.ReadFile(name)
+	if err != nil {
+		panic("schema: embedded schema missing: " + name)
+	}
+	return data
+}
+
+// ValidationError reports every schema violation found in a single
+// Validate call.
+type ValidationError struct {
+	Errors []gojsonschema.ResultError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, resultError := range e.Errors {
+		msgs[i] = resultError.String()
+	}
+	return fmt.Sprintf("schema: validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// Validate checks data against schema, returning a *ValidationError
+// listing every violation when data does not conform.
+func Validate(data []byte, schema []byte) error {
+	result, err := gojsonschema.Validate(
+		gojsonschema.NewBytesLoader(schema),
+		gojsonschema.NewBytesLoader(data),
+	)
+	if err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	if !result.Valid() {
+		return &ValidationError{Errors: result.Errors()}
+	}
+	return nil
+}
+
+// ValidateTrace validates a user-facing JSON trace against schema. Pass
+// TraceSchema for the baseline structural check, or a caller-authored
+// schema to additionally assert domain invariants on the trace (e.g.
+// "every state has field X of type Y").
+func ValidateTrace(trace []byte, schema []byte) error {
+	return Validate(trace, schema)
+}
@@ -0,0 +1,13 @@
+package schema
+
+import "testing"
+
+// An unrecognized status must still pass schema validation, so that it can
+// reach the Go-side typed-error handling (ModelatorError's
+// "unexpected_status" Kind) instead of being rejected here first.
+func TestValidateModelatorOutputAllowsUnexpectedStatus(t *testing.T) {
+	data := []byte(`{"status":"pending","result":{}}`)
+	if err := Validate(data, ModelatorOutputSchema); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Alexander-N/modelator/schema"
+)
+
+// Session is a long-running `modelator` process started in "serve" mode.
+// Instead of spawning a new process per call (as Modelator does), a Session
+// keeps a single `modelator serve` process alive and talks to it over
+// stdin/stdout using line-delimited JSON-RPC 2.0 requests and responses.
+// This avoids paying the JVM/TLC warm-up cost on every call.
+type Session struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	nextID uint64
+
+	mu      sync.Mutex
+	pending map[uint64]chan rpcResponse
+
+	closeOnce sync.Once
+	closeErr  error
+	done      chan struct{}
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// rpcError is the wire shape of a JSON-RPC error response. It is converted
+// to a *ModelatorError (see newModelatorErrorFromRPCError) before reaching
+// callers, so that Session and Modelator failures share one error type.
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// NewSession starts `modelator serve` and begins reading its responses in
+// the background. Callers must call Close when done with the session.
+func NewSession() (*Session, error) {
+	cmd := exec.Command("modelator", "serve")
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return newSession(cmd, stdin, stdout), nil
+}
+
+// newSession wires up a Session around an already-started command and its
+// stdin/stdout pipes. Split out from NewSession so tests can drive a
+// Session against a fake stdin/stdout pair instead of a real modelator
+// process.
+func newSession(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.ReadCloser) *Session {
+	s := &Session{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[uint64]chan rpcResponse),
+		done:    make(chan struct{}),
+	}
+
+	go s.readLoop(stdout)
+
+	return s
+}
+
+// readLoop reads one JSON-RPC response per line from the modelator process
+// and dispatches it to whichever call() is waiting on that response's id.
+func (s *Session) readLoop(stdout io.ReadCloser) {
+	defer close(s.done)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var resp rpcResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			// Ignore malformed lines (e.g. stray log output) rather than
+			// taking down the whole session.
+			continue
+		}
+
+		s.mu.Lock()
+		ch, ok := s.pending[resp.ID]
+		if ok {
+			delete(s.pending, resp.ID)
+		}
+		s.mu.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// call sends a JSON-RPC request and blocks until the matching response
+// arrives or the session is closed.
+func (s *Session) call(method string, params interface{}) (json.RawMessage, error) {
+	id := atomic.AddUint64(&s.nextID, 1)
+	ch := make(chan rpcResponse, 1)
+
+	s.mu.Lock()
+	s.pending[id] = ch
+	s.mu.Unlock()
+
+	req := rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}
+	line, err := json.Marshal(req)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return nil, err
+	}
+	line = append(line, '\n')
+
+	if _, err := s.stdin.Write(line); err != nil {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, newModelatorErrorFromRPCError(method, resp.Error)
+		}
+		return resp.Result, nil
+	case <-s.done:
+		return nil, errors.New("modelator: session closed before response was received")
+	}
+}
+
+// Close shuts the session down: it closes stdin (so modelator can exit
+// cleanly), waits for the process to exit, and fails any still-pending
+// calls.
+func (s *Session) Close() error {
+	s.closeOnce.Do(func() {
+		s.stdin.Close()
+		if s.cmd != nil {
+			s.closeErr = s.cmd.Wait()
+		}
+	})
+	return s.closeErr
+}
+
+// GenerateTests is the session-backed equivalent of the top-level
+// GenerateTests function.
+func (s *Session) GenerateTests(tlaTestsFile string, tlaConfigFile string) ([]GeneratedTest, error) {
+	var generatedTests []GeneratedTest
+	result, err := s.call("tla.generate-tests", []string{tlaTestsFile, tlaConfigFile})
+	if err != nil {
+		return generatedTests, err
+	}
+	if err := schema.Validate(result, schema.GeneratedTestSchema); err != nil {
+		return generatedTests, err
+	}
+	json.Unmarshal(result, &generatedTests)
+	return generatedTests, nil
+}
+
+// Test is the session-backed equivalent of the top-level Test function.
+func (s *Session) Test(tlaTestsFile string, tlaConfigFile string) (TlaTrace, error) {
+	var tlaTrace TlaTrace
+	result, err := s.call("tlc.test", []string{tlaTestsFile, tlaConfigFile})
+	if err != nil {
+		return tlaTrace, err
+	}
+	if err := schema.Validate(result, schema.TlaTraceSchema); err != nil {
+		return tlaTrace, err
+	}
+	json.Unmarshal(result, &tlaTrace)
+	return tlaTrace, nil
+}
+
+// TlaTraceToJsonTrace is the session-backed equivalent of the top-level
+// TlaTraceToJsonTrace function.
+func (s *Session) TlaTraceToJsonTrace(tlaTraceFile string) (JsonTrace, error) {
+	var jsonTrace JsonTrace
+	result, err := s.call("tla.tla-trace-to-json-trace", []string{tlaTraceFile})
+	if err != nil {
+		return jsonTrace, err
+	}
+	if err := schema.Validate(result, schema.JsonTraceSchema); err != nil {
+		return jsonTrace, err
+	}
+	json.Unmarshal(result, &jsonTrace)
+	return jsonTrace, nil
+}
+
+// Traces runs the generate-tests -> test -> tla-trace-to-json-trace
+// pipeline over this session, without spawning a fresh modelator process
+// for each step.
+func (s *Session) Traces(tlaTestsFile string, tlaConfigFile string) ([][]byte, error) {
+	var traces [][]byte
+
+	generatedTests, err := s.GenerateTests(tlaTestsFile, tlaConfigFile)
+	if err != nil {
+		return traces, err
+	}
+
+	for _, generatedTest := range generatedTests {
+		tlaTrace, err := s.Test(generatedTest.TlaFile, generatedTest.TlaConfigFile)
+		if err != nil {
+			return traces, err
+		}
+
+		jsonTrace, err := s.TlaTraceToJsonTrace(tlaTrace.TlaTraceFile)
+		if err != nil {
+			return traces, err
+		}
+
+		trace, err := ioutil.ReadFile(jsonTrace.JsonTraceFile)
+		if err != nil {
+			return traces, err
+		}
+		traces = append(traces, trace)
+	}
+
+	return traces, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+// fakeModelatorServer reads one JSON-RPC request line from stdin and
+// writes back a single response built by reply, echoing the request id.
+func fakeModelatorServer(t *testing.T, stdin io.Reader, stdout io.Writer, reply func(req rpcRequest) rpcResponse) {
+	t.Helper()
+
+	go func() {
+		decoder := json.NewDecoder(stdin)
+		for {
+			var req rpcRequest
+			if err := decoder.Decode(&req); err != nil {
+				return
+			}
+
+			resp := reply(req)
+			resp.ID = req.ID
+
+			line, err := json.Marshal(resp)
+			if err != nil {
+				return
+			}
+			if _, err := stdout.Write(append(line, '\n')); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func TestSessionCallDispatchesResponsesByID(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	fakeModelatorServer(t, stdinR, stdoutW, func(req rpcRequest) rpcResponse {
+		switch req.Method {
+		case "tla.generate-tests":
+			return rpcResponse{JSONRPC: "2.0", Result: json.RawMessage(`[{"tla_file":"a.tla","tla_config_file":"a.cfg"}]`)}
+		case "tlc.test":
+			return rpcResponse{JSONRPC: "2.0", Result: json.RawMessage(`{"tla_trace_file":"a.trace"}`)}
+		default:
+			return rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: -1, Message: "unknown method"}}
+		}
+	})
+
+	s := newSession(nil, stdinW, stdoutR)
+	defer s.Close()
+
+	generatedTests, err := s.GenerateTests("a.tla", "a.cfg")
+	if err != nil {
+		t.Fatalf("GenerateTests: %v", err)
+	}
+	if len(generatedTests) != 1 || generatedTests[0].TlaFile != "a.tla" {
+		t.Fatalf("GenerateTests returned %+v", generatedTests)
+	}
+
+	tlaTrace, err := s.Test("a.tla", "a.cfg")
+	if err != nil {
+		t.Fatalf("Test: %v", err)
+	}
+	if tlaTrace.TlaTraceFile != "a.trace" {
+		t.Fatalf("Test returned %+v", tlaTrace)
+	}
+}
+
+func TestSessionCallReturnsRPCError(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	fakeModelatorServer(t, stdinR, stdoutW, func(req rpcRequest) rpcResponse {
+		return rpcResponse{
+			JSONRPC: "2.0",
+			Error: &rpcError{
+				Code:    1,
+				Message: "tlc crashed",
+				Data:    json.RawMessage(`{"kind":"tlc_failure"}`),
+			},
+		}
+	})
+
+	s := newSession(nil, stdinW, stdoutR)
+	defer s.Close()
+
+	_, err := s.Test("a.tla", "a.cfg")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !errors.Is(err, ErrTlcFailure) {
+		t.Fatalf("expected errors.Is(err, ErrTlcFailure), got %v", err)
+	}
+}
+
+func TestSessionCallRejectsResultThatFailsSchema(t *testing.T) {
+	stdinR, stdinW := io.Pipe()
+	stdoutR, stdoutW := io.Pipe()
+
+	fakeModelatorServer(t, stdinR, stdoutW, func(req rpcRequest) rpcResponse {
+		// Missing the required "tla_trace_file" property.
+		return rpcResponse{JSONRPC: "2.0", Result: json.RawMessage(`{}`)}
+	})
+
+	s := newSession(nil, stdinW, stdoutR)
+	defer s.Close()
+
+	if _, err := s.Test("a.tla", "a.cfg"); err == nil {
+		t.Fatal("expected a schema validation error")
+	}
+}